@@ -0,0 +1,174 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/exporters/zipkin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+)
+
+// Supported -exporter flag values.
+const (
+	exporterOTLPGRPC = "otlp-grpc"
+	exporterOTLPHTTP = "otlp-http"
+	exporterJaeger   = "jaeger"
+	exporterZipkin   = "zipkin"
+	exporterStdout   = "stdout"
+)
+
+// zipkinDefaultEndpoint is Zipkin's own conventional collector endpoint,
+// used when -endpoint wasn't passed explicitly instead of the global
+// -endpoint default, which is shaped for otlp-grpc.
+const zipkinDefaultEndpoint = "http://localhost:9411/api/v2/spans"
+
+// isLocalExporter reports whether kind writes spans locally instead of
+// shipping them to a remote collector, in which case batching only adds
+// latency without any benefit.
+func isLocalExporter(kind string) bool {
+	return kind == exporterStdout
+}
+
+// newSpanExporter builds the trace exporter selected by the -exporter flag.
+// endpointSet indicates whether -endpoint was passed explicitly on the
+// command line; when it wasn't, OTLP exporters fall back to the
+// OTEL_EXPORTER_OTLP_* environment variables instead of the flag's default.
+func newSpanExporter(ctx context.Context, kind string, endpointSet bool) (sdktrace.SpanExporter, error) {
+	switch kind {
+	case "", exporterOTLPGRPC:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithTimeout(100 * time.Millisecond)}
+		if endpointSet {
+			opts = append(opts, otlptracegrpc.WithEndpoint(endpoint))
+		}
+		if otlpInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		} else {
+			creds, err := tlsConfig()
+			if err != nil {
+				return nil, err
+			}
+			if creds != nil {
+				opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(creds)))
+			}
+		}
+		if headers := otlpHeaderMap(); len(headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(headers))
+		}
+		if otlpCompression != "" {
+			opts = append(opts, otlptracegrpc.WithCompressor(otlpCompression))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+
+	case exporterOTLPHTTP:
+		opts := []otlptracehttp.Option{}
+		if endpointSet {
+			opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
+		}
+		if otlpInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		} else {
+			creds, err := tlsConfig()
+			if err != nil {
+				return nil, err
+			}
+			if creds != nil {
+				opts = append(opts, otlptracehttp.WithTLSClientConfig(creds))
+			}
+		}
+		if headers := otlpHeaderMap(); len(headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(headers))
+		}
+		if otlpCompression != "" {
+			opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		}
+		return otlptracehttp.New(ctx, opts...)
+
+	case exporterJaeger:
+		// The global -endpoint default is shaped for otlp-grpc; only pass it
+		// along here if the user actually chose it, and let the Jaeger
+		// exporter fall back to its own default collector endpoint
+		// (http://localhost:14268/api/traces, overridable via JAEGER_ENDPOINT)
+		// otherwise.
+		var opts []jaeger.CollectorEndpointOption
+		if endpointSet {
+			opts = append(opts, jaeger.WithEndpoint(endpoint))
+		}
+		return jaeger.New(jaeger.WithCollectorEndpoint(opts...))
+
+	case exporterZipkin:
+		zipkinEndpoint := endpoint
+		if !endpointSet {
+			zipkinEndpoint = zipkinDefaultEndpoint
+		}
+		return zipkin.New(zipkinEndpoint)
+
+	case exporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+
+	default:
+		return nil, fmt.Errorf("unknown -exporter %q: must be one of %s, %s, %s, %s, %s",
+			kind, exporterOTLPGRPC, exporterOTLPHTTP, exporterJaeger, exporterZipkin, exporterStdout)
+	}
+}
+
+// newSpanProcessor wraps exp in the span processor appropriate for kind:
+// a batch processor for exporters that ship spans off-box, or a simple
+// processor for the stdout exporter, which has nothing to gain from
+// buffering.
+func newSpanProcessor(exp sdktrace.SpanExporter, kind string) sdktrace.SpanProcessor {
+	if isLocalExporter(kind) {
+		return sdktrace.NewSimpleSpanProcessor(exp)
+	}
+	return sdktrace.NewBatchSpanProcessor(exp)
+}
+
+// otlpHeaderMap parses the -otlp-headers flag (a comma-separated list of
+// key=value pairs) into a map suitable for otlptracegrpc/otlptracehttp's
+// WithHeaders. An empty flag defers entirely to OTEL_EXPORTER_OTLP_HEADERS,
+// which the exporters already read on their own.
+func otlpHeaderMap() map[string]string {
+	if otlpHeaders == "" {
+		return nil
+	}
+	headers := make(map[string]string)
+	for _, kv := range strings.Split(otlpHeaders, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return headers
+}
+
+// tlsConfig loads the CA certificate named by -otlp-ca-cert, if any, for
+// verifying the collector's certificate. It returns a nil *tls.Config when
+// no CA file was given, letting the exporter fall back to the system pool.
+func tlsConfig() (*tls.Config, error) {
+	if otlpCACert == "" {
+		return nil, nil
+	}
+	b, err := ioutil.ReadFile(otlpCACert)
+	if err != nil {
+		return nil, fmt.Errorf("reading -otlp-ca-cert: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in -otlp-ca-cert %q", otlpCACert)
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOtlpHeaderMap(t *testing.T) {
+	tests := []struct {
+		description string
+		flag        string
+		want        map[string]string
+	}{
+		{
+			description: "empty flag defers to OTEL_EXPORTER_OTLP_HEADERS",
+			flag:        "",
+			want:        nil,
+		},
+		{
+			description: "single key=value pair",
+			flag:        "api-key=secret",
+			want:        map[string]string{"api-key": "secret"},
+		},
+		{
+			description: "multiple pairs with surrounding whitespace",
+			flag:        "a=1, b=2 ,c=3",
+			want:        map[string]string{"a": "1", "b": "2", "c": "3"},
+		},
+		{
+			description: "malformed pair without '=' is skipped",
+			flag:        "a=1,nope,b=2",
+			want:        map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			description: "value containing '=' is kept whole",
+			flag:        "authorization=Bearer a=b",
+			want:        map[string]string{"authorization": "Bearer a=b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			saved := otlpHeaders
+			defer func() { otlpHeaders = saved }()
+
+			otlpHeaders = tt.flag
+			got := otlpHeaderMap()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("otlpHeaderMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsLocalExporter(t *testing.T) {
+	tests := []struct {
+		kind string
+		want bool
+	}{
+		{exporterStdout, true},
+		{exporterOTLPGRPC, false},
+		{exporterOTLPHTTP, false},
+		{exporterJaeger, false},
+		{exporterZipkin, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			if got := isLocalExporter(tt.kind); got != tt.want {
+				t.Errorf("isLocalExporter(%q) = %v, want %v", tt.kind, got, tt.want)
+			}
+		})
+	}
+}
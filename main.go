@@ -3,15 +3,15 @@
 // license that can be found in the LICENSE file.
 
 // go-test-trace is a tiny program that generates OpenTelemetry
-// traces when testing a Go package.
+// traces and metrics when testing a Go package.
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -19,8 +19,8 @@ import (
 	"time"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
@@ -29,11 +29,17 @@ import (
 )
 
 var (
-	endpoint    string
-	name        string
-	stdin       bool
-	traceparent string
-	help        bool
+	endpoint        string
+	name            string
+	stdin           bool
+	traceparent     string
+	help            bool
+	exporterKind    string
+	otlpHeaders     string
+	otlpInsecure    bool
+	otlpCompression string
+	otlpCACert      string
+	metricsEndpoint string
 )
 
 type spanData struct {
@@ -51,42 +57,76 @@ func main() {
 	fset.BoolVar(&stdin, "stdin", false, "")
 	fset.BoolVar(&help, "help", false, "")
 	fset.StringVar(&traceparent, "traceparent", "", "")
+	fset.StringVar(&exporterKind, "exporter", exporterOTLPGRPC, "")
+	fset.StringVar(&otlpHeaders, "otlp-headers", "", "")
+	fset.BoolVar(&otlpInsecure, "otlp-insecure", true, "")
+	fset.StringVar(&otlpCompression, "otlp-compression", "", "")
+	fset.StringVar(&otlpCACert, "otlp-ca-cert", "", "")
+	fset.StringVar(&metricsEndpoint, "metrics-endpoint", "", "")
 	fset.Usage = func() {} // don't error instead pass remaining arguments to go test
+
+	// -endpoint defaults to a local collector address; track whether the
+	// user actually passed it so OTLP exporters can fall back to
+	// OTEL_EXPORTER_OTLP_* instead of that default.
+	endpointSet := false
+	for _, arg := range os.Args[1:] {
+		if arg == "-endpoint" || strings.HasPrefix(arg, "-endpoint=") {
+			endpointSet = true
+			break
+		}
+	}
 	fset.Parse(os.Args[1:])
 
 	if help {
 		fmt.Println(usageText)
 		os.Exit(0)
 	}
-	if err := trace(fset.Args()); err != nil {
+	if err := trace(fset.Args(), endpointSet); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func trace(args []string) error {
+func trace(args []string, endpointSet bool) error {
 	ctx := context.Background()
-	traceExporter, err := otlptracegrpc.New(ctx,
-		otlptracegrpc.WithInsecure(),
-		otlptracegrpc.WithEndpoint(endpoint),
-		otlptracegrpc.WithTimeout(100*time.Millisecond),
-	)
+	traceExporter, err := newSpanExporter(ctx, exporterKind, endpointSet)
 	if err != nil {
 		return err
 	}
 	res, err := resource.New(ctx, resource.WithAttributes(
-		semconv.ServiceNameKey.String("go test"),
+		append([]attribute.KeyValue{semconv.ServiceNameKey.String("go test")}, runResourceAttributes()...)...,
 	))
 	if err != nil {
 		return err
 	}
 	tracerProvider := sdktrace.NewTracerProvider(
 		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-		sdktrace.WithSpanProcessor(sdktrace.NewSimpleSpanProcessor(traceExporter)),
+		sdktrace.WithSpanProcessor(newSpanProcessor(traceExporter, exporterKind)),
 		sdktrace.WithResource(res),
 	)
 	otel.SetTracerProvider(tracerProvider)
 	t := otel.Tracer(name)
 
+	// Only build a meter provider when there's somewhere to send metrics and
+	// something to report them for: -stdin never calls onStart/onFinish, and
+	// without -metrics-endpoint or an explicit -endpoint there's no real
+	// collector address, just the otlp-grpc-shaped global default.
+	var testMetrics *testMetrics
+	if !stdin && (metricsEndpoint != "" || endpointSet) {
+		metricsEp := metricsEndpoint
+		if metricsEp == "" {
+			metricsEp = endpoint
+		}
+		testMetrics, err = newTestMetrics(ctx, metricsEp, res)
+		if err != nil {
+			return err
+		}
+	}
+	defer func() {
+		if err := testMetrics.Shutdown(context.Background()); err != nil {
+			log.Printf("Failed shutting down the meter provider: %v", err)
+		}
+	}()
+
 	// If there is a parent trace, participate into it.
 	// If not, create a new root span.
 	if traceparent != "" {
@@ -114,27 +154,95 @@ func trace(args []string) error {
 	goTestArgs := append([]string{"test"}, args...)
 	goTestArgs = append(goTestArgs, "-json")
 	cmd := exec.Command("go", goTestArgs...)
+	sc := globalSpan.SpanContext()
 	cmd.Env = append(
 		os.Environ(),
-		fmt.Sprintf("TRACEPARENT=%q", globalSpan.SpanContext().TraceID()),
+		fmt.Sprintf("TRACEPARENT=00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags()),
 	)
+	if endpointSet {
+		// Only override the child's collector endpoint if the user actually
+		// passed -endpoint; otherwise let testtrace fall back to whatever
+		// OTEL_EXPORTER_OTLP_ENDPOINT the environment already provides.
+		cmd.Env = append(cmd.Env, fmt.Sprintf("OTEL_EXPORTER_OTLP_ENDPOINT=%s", endpoint))
+	}
 	r, err := cmd.StdoutPipe()
 	if err != nil {
 		log.Fatal(err)
 	}
-	decoder := json.NewDecoder(r)
+	cmd.Stderr = os.Stderr
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 	go func() {
-		for decoder.More() {
+		var (
+			buildFailurePkg    string
+			buildFailureOutput strings.Builder
+			failure            failureCapture
+		)
+		for scanner.Scan() {
+			rawLine := scanner.Text()
 			var data goTestOutput
-			if err := decoder.Decode(&data); err != nil {
-				if err == io.EOF {
-					return
+			if err := json.Unmarshal([]byte(rawLine), &data); err != nil {
+				// A failed build or package setup is reported before the
+				// test binary (and its -json wrapper) ever runs, so `go
+				// test` writes it as plain text on stdout instead of a
+				// JSON "output" action. Watch for it here the same way
+				// parser.go's stdin path does.
+				switch {
+				case buildFailurePkg != "":
+					buildFailureOutput.WriteString(rawLine)
+					buildFailureOutput.WriteByte('\n')
+					if buildFailedRegex.MatchString(rawLine) {
+						_, span := t.Start(globalCtx, buildFailurePkg)
+						span.SetStatus(codes.Error, "build failed")
+						span.AddEvent("exception", oteltrace.WithAttributes(
+							attribute.String("exception.type", "build failed"),
+							attribute.String("exception.message", strings.TrimSpace(buildFailureOutput.String())),
+						))
+						span.End()
+						buildFailurePkg = ""
+					}
+				case compileErrorRegex.MatchString(rawLine):
+					buildFailurePkg = strings.TrimPrefix(rawLine, "# ")
+					buildFailureOutput.Reset()
+					buildFailureOutput.WriteString(rawLine)
+					buildFailureOutput.WriteByte('\n')
 				}
-				log.Printf("Failed to decode JSON: %v", err)
+				fmt.Println(rawLine)
+				continue
 			}
 
 			key := testKey(data.Package, data.Test)
 			switch data.Action {
+			case "output":
+				line := strings.TrimRight(data.Output, "\n")
+				if line == "" {
+					break
+				}
+				var span oteltrace.Span
+				if sd, ok := collectedSpans[key]; ok {
+					span = sd.span
+				} else if sd, ok := collectedSpans[data.Package]; ok {
+					span = sd.span
+				}
+				if span != nil {
+					span.AddEvent("log", oteltrace.WithAttributes(
+						attribute.String("log.message", line),
+						attribute.String("log.severity", "INFO"),
+					))
+				}
+				switch {
+				case panicRegex.MatchString(line):
+					fspan, owned := fallbackFailureSpan(t, globalCtx, span, "panic")
+					failure.start(fspan, owned, "panic", line)
+				case dataRaceRegex.MatchString(line):
+					fspan, owned := fallbackFailureSpan(t, globalCtx, span, "data race")
+					failure.start(fspan, owned, "data race", line)
+				case failure.active():
+					failure.add(line)
+					if strings.HasPrefix(line, "exit status") {
+						failure.finish()
+					}
+				}
 			case "start":
 				ctx, span := t.Start(globalCtx, data.Package, oteltrace.WithTimestamp(data.Time))
 				collectedSpans[key] = &spanData{
@@ -143,12 +251,21 @@ func trace(args []string) error {
 					startTime: data.Time,
 				}
 			case "run":
-				ctx, span := t.Start(parentContext(globalCtx, data.Package, data.Test), data.Test, oteltrace.WithTimestamp(data.Time))
+				ctx, span := t.Start(parentContext(globalCtx, data.Package, data.Test), data.Test,
+					oteltrace.WithTimestamp(data.Time),
+					oteltrace.WithAttributes(
+						semconv.CodeFunctionKey.String(data.Test),
+						semconv.CodeNamespaceKey.String(data.Package),
+					),
+				)
 				collectedSpans[key] = &spanData{
 					ctx:       ctx,
 					span:      span,
 					startTime: data.Time,
 				}
+				if data.Test != "" {
+					testMetrics.onStart(ctx, data.Package, data.Test)
+				}
 			case "pass", "fail", "skip":
 				spanData, ok := collectedSpans[key]
 				if !ok {
@@ -157,10 +274,19 @@ func trace(args []string) error {
 				if data.Action == "fail" {
 					spanData.span.SetStatus(codes.Error, "")
 				}
+				if data.Test != "" {
+					spanData.span.SetAttributes(attribute.String("test.result", data.Action))
+				}
 				spanData.span.End(oteltrace.WithTimestamp(data.Time))
+				if data.Test != "" {
+					testMetrics.onFinish(spanData.ctx, data.Package, data.Test, data.Action, data.Time.Sub(spanData.startTime))
+				}
 			}
 			fmt.Print(data.Output)
 		}
+		if err := scanner.Err(); err != nil {
+			log.Printf("Error reading go test output: %v", err)
+		}
 	}()
 	return cmd.Run()
 }
@@ -224,10 +350,34 @@ const usageText = `Usage:
 go-test-trace [flags...] [go test flags...]
 
 Flags:
--name        Name of the trace span created for the test, optional.
--endpoint    OpenTelemetry gRPC collector endpoint, 127.0.0.1:55680 by default.
--traceparent Trace to participate into if any, in W3C Trace Context format.
--stdin       Parse go test verbose output from stdin.
--help        Print this text.
+-name             Name of the trace span created for the test, optional.
+-endpoint         Collector endpoint. When left unset, otlp-grpc and otlp-http
+                   fall back to OTEL_EXPORTER_OTLP_*, and jaeger and zipkin
+                   to their own default collector endpoints.
+-exporter         Exporter to send spans to: otlp-grpc (default), otlp-http,
+                   jaeger, zipkin, stdout.
+-otlp-headers     Extra headers for the otlp-grpc/otlp-http exporters, as a
+                   comma-separated list of key=value pairs.
+-otlp-insecure    Disable TLS for the otlp-grpc/otlp-http exporters. true by
+                   default.
+-otlp-compression Compression to use for the otlp-grpc/otlp-http exporters,
+                   e.g. "gzip". Disabled by default.
+-otlp-ca-cert     PEM-encoded CA certificate to verify the collector with,
+                   when -otlp-insecure=false.
+-metrics-endpoint OTLP gRPC collector endpoint for test metrics (pass/fail
+                   counts, durations, running gauge). Defaults to -endpoint.
+                   Metrics are skipped entirely under -stdin, or when neither
+                   this flag nor -endpoint was passed.
+-traceparent      Trace to participate into if any, in W3C Trace Context format.
+-stdin            Parse go test verbose output from stdin.
+-help             Print this text.
+
+go-test-trace propagates its root span to the spawned go test process via
+the TRACEPARENT environment variable, and sets OTEL_EXPORTER_OTLP_ENDPOINT
+to match only when -endpoint was passed explicitly, so OTEL_EXPORTER_OTLP_*
+set in the environment still reaches it otherwise. Code under test can join
+the same trace with the testtrace subpackage:
+
+    ctx, span := testtrace.StartSpan(t)
 
 Run "go help test" for go test flags.`
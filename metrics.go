@@ -0,0 +1,113 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/unit"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// testMetrics holds the instruments go-test-trace records alongside spans:
+// pass/fail/skip/start counters, a duration histogram and a gauge of tests
+// currently running, all labeled by package and test name.
+type testMetrics struct {
+	controller *controller.Controller
+
+	started  metric.Int64Counter
+	passed   metric.Int64Counter
+	failed   metric.Int64Counter
+	skipped  metric.Int64Counter
+	duration metric.Float64Histogram
+	running  metric.Int64UpDownCounter
+}
+
+// newTestMetrics builds a MeterProvider pointed at the OTLP metrics
+// collector and the instruments used to report test results to it.
+func newTestMetrics(ctx context.Context, endpoint string, res *resource.Resource) (*testMetrics, error) {
+	client := otlpmetricgrpc.NewClient(
+		otlpmetricgrpc.WithInsecure(),
+		otlpmetricgrpc.WithEndpoint(endpoint),
+		otlpmetricgrpc.WithTimeout(100*time.Millisecond),
+	)
+	exp, err := otlpmetric.New(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	cont := controller.New(
+		processor.NewFactory(simple.NewWithHistogramDistribution(), exp),
+		controller.WithExporter(exp),
+		controller.WithResource(res),
+		controller.WithCollectPeriod(10*time.Second),
+	)
+	if err := cont.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	meter := cont.Meter(name)
+	m := metric.Must(meter)
+	return &testMetrics{
+		controller: cont,
+		started:    m.NewInt64Counter("test.started", metric.WithDescription("Number of tests started")),
+		passed:     m.NewInt64Counter("test.passed", metric.WithDescription("Number of tests passed")),
+		failed:     m.NewInt64Counter("test.failed", metric.WithDescription("Number of tests failed")),
+		skipped:    m.NewInt64Counter("test.skipped", metric.WithDescription("Number of tests skipped")),
+		duration:   m.NewFloat64Histogram("test.duration", metric.WithDescription("Test duration"), metric.WithUnit(unit.Milliseconds)),
+		running:    m.NewInt64UpDownCounter("test.running", metric.WithDescription("Number of tests currently running")),
+	}, nil
+}
+
+// onStart and onFinish are no-ops on a nil *testMetrics, so callers don't
+// need to special-case the -stdin and no-endpoint-configured cases where
+// trace() skips building a meter provider altogether.
+
+func (tm *testMetrics) onStart(ctx context.Context, pkg, test string) {
+	if tm == nil {
+		return
+	}
+	labels := testLabels(pkg, test)
+	tm.started.Add(ctx, 1, labels...)
+	tm.running.Add(ctx, 1, labels...)
+}
+
+func (tm *testMetrics) onFinish(ctx context.Context, pkg, test string, action string, dur time.Duration) {
+	if tm == nil {
+		return
+	}
+	labels := testLabels(pkg, test)
+	tm.running.Add(ctx, -1, labels...)
+	tm.duration.Record(ctx, float64(dur.Milliseconds()), labels...)
+	switch action {
+	case "pass":
+		tm.passed.Add(ctx, 1, labels...)
+	case "fail":
+		tm.failed.Add(ctx, 1, labels...)
+	case "skip":
+		tm.skipped.Add(ctx, 1, labels...)
+	}
+}
+
+func testLabels(pkg, test string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("package", pkg),
+		attribute.String("test", test),
+	}
+}
+
+func (tm *testMetrics) Shutdown(ctx context.Context) error {
+	if tm == nil {
+		return nil
+	}
+	return tm.controller.Stop(ctx)
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestTestLabels(t *testing.T) {
+	tests := []struct {
+		description string
+		pkg         string
+		test        string
+		want        []attribute.KeyValue
+	}{
+		{
+			description: "package and test name",
+			pkg:         "github.com/rakyll/go-test-xray",
+			test:        "TestFoo",
+			want: []attribute.KeyValue{
+				attribute.String("package", "github.com/rakyll/go-test-xray"),
+				attribute.String("test", "TestFoo"),
+			},
+		},
+		{
+			description: "empty test name for a package-level event",
+			pkg:         "github.com/rakyll/go-test-xray",
+			test:        "",
+			want: []attribute.KeyValue{
+				attribute.String("package", "github.com/rakyll/go-test-xray"),
+				attribute.String("test", ""),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := testLabels(tt.pkg, tt.test)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("testLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
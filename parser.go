@@ -10,16 +10,31 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 type parser struct {
 	globalCtx context.Context
 	tracer    oteltrace.Tracer
+
+	// current is the name of the most recently started test, cleared when
+	// that test ends (even if other tests are still open). Output lines that
+	// aren't a recognized marker are attached to it as span events.
+	current string
+
+	// buildFailurePkg is set while buffering the output of a failed build
+	// ("# pkg" through "FAIL\tpkg [build failed]"), empty otherwise.
+	buildFailurePkg    string
+	buildFailureOutput strings.Builder
+
+	failure failureCapture
 }
 
 func newParser(ctx context.Context, tracer oteltrace.Tracer) (*parser, error) {
@@ -46,6 +61,10 @@ func (p *parser) parseLine(line string) {
 	trimmed := strings.TrimSpace(line)
 
 	switch {
+	case compileErrorRegex.MatchString(trimmed):
+		p.startBuildFailure(trimmed)
+	case buildFailedRegex.MatchString(trimmed):
+		p.endBuildFailure()
 	case strings.HasPrefix(trimmed, "ok"):
 		// Do nothing.
 	case strings.HasPrefix(trimmed, "PASS"):
@@ -61,6 +80,12 @@ func (p *parser) parseLine(line string) {
 	case strings.HasPrefix(trimmed, "=== RUN"):
 		p.start(trimmed)
 
+		// parallel test scheduling
+	case strings.HasPrefix(trimmed, "=== PAUSE"):
+		p.schedulingEvent(trimmed, "test.pause")
+	case strings.HasPrefix(trimmed, "=== CONT"):
+		p.schedulingEvent(trimmed, "test.cont")
+
 		// finished
 	case strings.HasPrefix(trimmed, "--- PASS"):
 		fallthrough
@@ -71,30 +96,185 @@ func (p *parser) parseLine(line string) {
 	case strings.HasPrefix(trimmed, "--- FAIL"):
 		// end segment with error
 		p.end(trimmed, true)
+
+	case benchmarkRegex.MatchString(trimmed):
+		p.benchmark(trimmed)
+
+	default:
+		p.logLine(trimmed)
 	}
 
 }
 
 func (p *parser) start(line string) error {
 	name := parseName(line)
-	_, span := p.tracer.Start(p.globalCtx, name)
+	ctx, span := p.tracer.Start(parentContextForName(p.globalCtx, name), name, oteltrace.WithAttributes(
+		semconv.CodeFunctionKey.String(name),
+	))
 	collectedSpans[name] = &spanData{
+		ctx:       ctx,
 		span:      span,
 		startTime: time.Now(),
 	}
+	p.current = name
 	return nil
 }
 
+// parentContextForName mirrors the JSON path's parentContext: for a
+// sub-test "a/b/c" it looks for the nearest already-open ancestor span,
+// trying "a/b" then "a", so sub-tests are parented under their enclosing
+// test instead of becoming siblings of it.
+func parentContextForName(ctx context.Context, name string) context.Context {
+	until := len(name)
+	for {
+		sep := strings.LastIndex(name[:until], "/")
+		if sep == -1 {
+			return ctx
+		}
+		until = sep
+		if data, ok := collectedSpans[name[:until]]; ok {
+			return data.ctx
+		}
+	}
+}
+
+// schedulingEvent records an "=== PAUSE"/"=== CONT" line as a span event on
+// the named test, representing a gap in a parallel test's scheduling.
+func (p *parser) schedulingEvent(line, eventName string) {
+	name := parseName(line)
+	if data, ok := collectedSpans[name]; ok {
+		data.span.AddEvent(eventName)
+	}
+}
+
+// benchmarkRegex matches a `go test -bench` result line, e.g.:
+// BenchmarkFib-8   	 5000000	       245 ns/op	      32 B/op	       2 allocs/op
+var benchmarkRegex = regexp.MustCompile(`^(Benchmark\S+?)(?:-(\d+))?\s+(\d+)\s+([\d.]+) ns/op(?:\s+(\d+) B/op)?(?:\s+(\d+) allocs/op)?`)
+
+// benchmark records a finished `-bench` line as its own span: unlike tests,
+// a benchmark's result line is all the output there is, so the span is
+// started and ended together, with its timestamps derived from the
+// reported iteration count and ns/op.
+func (p *parser) benchmark(line string) {
+	m := benchmarkRegex.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	name, gomaxprocs, iterations, nsPerOp, bytesPerOp, allocsPerOp := m[1], m[2], m[3], m[4], m[5], m[6]
+
+	attrs := []attribute.KeyValue{semconv.CodeFunctionKey.String(name)}
+	if gomaxprocs != "" {
+		if n, err := strconv.Atoi(gomaxprocs); err == nil {
+			attrs = append(attrs, attribute.Int("benchmark.gomaxprocs", n))
+		}
+	}
+	iters, _ := strconv.ParseInt(iterations, 10, 64)
+	attrs = append(attrs, attribute.Int64("benchmark.iterations", iters))
+	ns, _ := strconv.ParseFloat(nsPerOp, 64)
+	attrs = append(attrs, attribute.Float64("benchmark.ns_per_op", ns))
+	if bytesPerOp != "" {
+		if b, err := strconv.ParseInt(bytesPerOp, 10, 64); err == nil {
+			attrs = append(attrs, attribute.Int64("benchmark.bytes_per_op", b))
+		}
+	}
+	if allocsPerOp != "" {
+		if a, err := strconv.ParseInt(allocsPerOp, 10, 64); err == nil {
+			attrs = append(attrs, attribute.Int64("benchmark.allocs_per_op", a))
+		}
+	}
+
+	start := time.Now()
+	ctx, span := p.tracer.Start(parentContextForName(p.globalCtx, name), name, oteltrace.WithAttributes(attrs...))
+	collectedSpans[name] = &spanData{ctx: ctx, span: span, startTime: start}
+	span.End(oteltrace.WithTimestamp(start.Add(time.Duration(float64(iters) * ns))))
+}
+
 func (p *parser) end(line string, errored bool) {
 	name, dur := parseNameAndDuration(line)
 	data, ok := collectedSpans[name]
 	if !ok {
 		return
 	}
+	result := "pass"
 	if errored {
 		data.span.SetStatus(codes.Error, "")
+		result = "fail"
 	}
+	data.span.SetAttributes(attribute.String("test.result", result))
 	data.span.End(oteltrace.WithTimestamp(data.startTime.Add(dur)))
+	if p.current == name {
+		p.current = ""
+	}
+}
+
+// logLine attaches an output line that isn't a recognized marker to the
+// currently running test's span as a log event, and watches for panics and
+// data races spanning the following lines.
+func (p *parser) logLine(line string) {
+	if p.buildFailurePkg != "" {
+		p.buildFailureOutput.WriteString(line)
+		p.buildFailureOutput.WriteByte('\n')
+		return
+	}
+	if line == "" {
+		return
+	}
+
+	span := p.currentSpan()
+	if span != nil {
+		span.AddEvent("log", oteltrace.WithAttributes(
+			attribute.String("log.message", line),
+			attribute.String("log.severity", "INFO"),
+		))
+	}
+
+	switch {
+	case panicRegex.MatchString(line):
+		fspan, owned := fallbackFailureSpan(p.tracer, p.globalCtx, span, "panic")
+		p.failure.start(fspan, owned, "panic", line)
+	case dataRaceRegex.MatchString(line):
+		fspan, owned := fallbackFailureSpan(p.tracer, p.globalCtx, span, "data race")
+		p.failure.start(fspan, owned, "data race", line)
+	case p.failure.active():
+		p.failure.add(line)
+		if strings.HasPrefix(line, "exit status") {
+			p.failure.finish()
+		}
+	}
+}
+
+func (p *parser) currentSpan() oteltrace.Span {
+	data, ok := collectedSpans[p.current]
+	if !ok {
+		return nil
+	}
+	return data.span
+}
+
+// startBuildFailure begins buffering the output of a failed build, starting
+// at its "# pkg" header line.
+func (p *parser) startBuildFailure(headerLine string) {
+	p.buildFailurePkg = strings.TrimPrefix(headerLine, "# ")
+	p.buildFailureOutput.Reset()
+	p.buildFailureOutput.WriteString(headerLine)
+	p.buildFailureOutput.WriteByte('\n')
+}
+
+// endBuildFailure closes out a buffered build failure by recording it as its
+// own errored span, since a build failure means the package's tests never
+// ran and so never got a span of their own.
+func (p *parser) endBuildFailure() {
+	if p.buildFailurePkg == "" {
+		return
+	}
+	_, span := p.tracer.Start(p.globalCtx, p.buildFailurePkg)
+	span.SetStatus(codes.Error, "build failed")
+	span.AddEvent("exception", oteltrace.WithAttributes(
+		attribute.String("exception.type", "build failed"),
+		attribute.String("exception.message", strings.TrimSpace(p.buildFailureOutput.String())),
+	))
+	span.End()
+	p.buildFailurePkg = ""
 }
 
 func parseName(line string) string {
@@ -110,7 +290,71 @@ func parseNameAndDuration(line string) (string, time.Duration) {
 	return name, dur
 }
 
+// fallbackFailureSpan returns span if it's non-nil, or else starts a new
+// orphan span under ctx, named for kind. It's used when a panic or data
+// race is reported with no test or package span currently open (e.g. a
+// panic in TestMain, or a crash after the last test's result line), so the
+// crash still leaves a span behind instead of silently vanishing. The
+// returned bool reports whether the caller now owns the span's lifetime:
+// true for a fallback span, since nothing else will ever end it.
+func fallbackFailureSpan(t oteltrace.Tracer, ctx context.Context, span oteltrace.Span, kind string) (oteltrace.Span, bool) {
+	if span != nil {
+		return span, false
+	}
+	_, span = t.Start(ctx, kind)
+	return span, true
+}
+
+// failureCapture buffers the output lines of a panic or data race report so
+// they can be attached to the offending span as a single exception event
+// once the report ends.
+type failureCapture struct {
+	span  oteltrace.Span
+	kind  string
+	owned bool // true if finish must End span itself: it's a fallbackFailureSpan, not a test's own span
+	buf   strings.Builder
+}
+
+func (f *failureCapture) start(span oteltrace.Span, owned bool, kind, line string) {
+	f.span = span
+	f.kind = kind
+	f.owned = owned
+	f.buf.Reset()
+	f.buf.WriteString(line)
+	f.buf.WriteByte('\n')
+}
+
+func (f *failureCapture) active() bool {
+	return f.kind != ""
+}
+
+func (f *failureCapture) add(line string) {
+	f.buf.WriteString(line)
+	f.buf.WriteByte('\n')
+}
+
+func (f *failureCapture) finish() {
+	if f.span != nil {
+		f.span.SetStatus(codes.Error, f.kind)
+		f.span.AddEvent("exception", oteltrace.WithAttributes(
+			attribute.String("exception.type", f.kind),
+			attribute.String("exception.stacktrace", strings.TrimRight(f.buf.String(), "\n")),
+		))
+		if f.owned {
+			f.span.End()
+		}
+	}
+	f.span = nil
+	f.kind = ""
+	f.owned = false
+}
+
 var (
 	testNameRegex             = regexp.MustCompile(`(Test.+)`)
 	testNameWithDurationRegex = regexp.MustCompile(`(Test.+)\s\(([\w|\.]+)\)`)
+
+	compileErrorRegex = regexp.MustCompile(`^# \S`)
+	buildFailedRegex  = regexp.MustCompile(`^FAIL\t\S+ \[(build|setup) failed\]`)
+	panicRegex        = regexp.MustCompile(`^panic: `)
+	dataRaceRegex     = regexp.MustCompile(`^WARNING: DATA RACE`)
 )
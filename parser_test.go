@@ -7,6 +7,7 @@
 package main
 
 import (
+	"context"
 	"testing"
 	"time"
 )
@@ -44,3 +45,120 @@ func TestParseNameAndDuration(t *testing.T) {
 		})
 	}
 }
+
+type ctxMarkerKey struct{}
+
+func withMarker(marker string) context.Context {
+	return context.WithValue(context.Background(), ctxMarkerKey{}, marker)
+}
+
+func TestParentContextForName(t *testing.T) {
+	tests := []struct {
+		description string
+		name        string
+		open        map[string]string // names already in collectedSpans, to their context's marker
+		wantMarker  string            // marker of the context parentContextForName should return; "" means the fallback context
+	}{
+		{
+			description: "no ancestor open falls back to the given context",
+			name:        "TestFoo/bar",
+			wantMarker:  "",
+		},
+		{
+			description: "direct parent is open",
+			name:        "TestFoo/bar",
+			open:        map[string]string{"TestFoo": "parent"},
+			wantMarker:  "parent",
+		},
+		{
+			description: "grandparent is open but immediate parent is not",
+			name:        "TestFoo/bar/baz",
+			open:        map[string]string{"TestFoo": "grandparent"},
+			wantMarker:  "grandparent",
+		},
+		{
+			description: "nearest open ancestor wins over a further one",
+			name:        "TestFoo/bar/baz",
+			open:        map[string]string{"TestFoo": "grandparent", "TestFoo/bar": "parent"},
+			wantMarker:  "parent",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			saved := collectedSpans
+			defer func() { collectedSpans = saved }()
+
+			collectedSpans = make(map[string]*spanData, len(tt.open))
+			for name, marker := range tt.open {
+				collectedSpans[name] = &spanData{ctx: withMarker(marker)}
+			}
+
+			wantMarker := tt.wantMarker
+			if wantMarker == "" {
+				wantMarker = "fallback"
+			}
+			got := parentContextForName(withMarker("fallback"), tt.name)
+			if gotMarker, _ := got.Value(ctxMarkerKey{}).(string); gotMarker != wantMarker {
+				t.Errorf("parentContextForName() marker = %v, want %v", gotMarker, wantMarker)
+			}
+		})
+	}
+}
+
+func TestBenchmarkRegex(t *testing.T) {
+	tests := []struct {
+		description     string
+		line            string
+		wantName        string
+		wantGOMAXPROCS  string
+		wantIterations  string
+		wantNsPerOp     string
+		wantBytesPerOp  string
+		wantAllocsPerOp string
+	}{
+		{
+			description:     "full result line with gomaxprocs, bytes and allocs",
+			line:            "BenchmarkFib-8   \t 5000000\t       245 ns/op\t      32 B/op\t       2 allocs/op",
+			wantName:        "BenchmarkFib",
+			wantGOMAXPROCS:  "8",
+			wantIterations:  "5000000",
+			wantNsPerOp:     "245",
+			wantBytesPerOp:  "32",
+			wantAllocsPerOp: "2",
+		},
+		{
+			description:    "no gomaxprocs suffix, no bytes/allocs columns",
+			line:           "BenchmarkNoAllocs\t1000000000\t         0.285 ns/op",
+			wantName:       "BenchmarkNoAllocs",
+			wantIterations: "1000000000",
+			wantNsPerOp:    "0.285",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			m := benchmarkRegex.FindStringSubmatch(tt.line)
+			if m == nil {
+				t.Fatalf("benchmarkRegex didn't match %q", tt.line)
+			}
+			name, gomaxprocs, iterations, nsPerOp, bytesPerOp, allocsPerOp := m[1], m[2], m[3], m[4], m[5], m[6]
+			if name != tt.wantName {
+				t.Errorf("name = %v, want %v", name, tt.wantName)
+			}
+			if gomaxprocs != tt.wantGOMAXPROCS {
+				t.Errorf("gomaxprocs = %v, want %v", gomaxprocs, tt.wantGOMAXPROCS)
+			}
+			if iterations != tt.wantIterations {
+				t.Errorf("iterations = %v, want %v", iterations, tt.wantIterations)
+			}
+			if nsPerOp != tt.wantNsPerOp {
+				t.Errorf("ns_per_op = %v, want %v", nsPerOp, tt.wantNsPerOp)
+			}
+			if bytesPerOp != tt.wantBytesPerOp {
+				t.Errorf("bytes_per_op = %v, want %v", bytesPerOp, tt.wantBytesPerOp)
+			}
+			if allocsPerOp != tt.wantAllocsPerOp {
+				t.Errorf("allocs_per_op = %v, want %v", allocsPerOp, tt.wantAllocsPerOp)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// Non-standard resource attribute keys: the OTel semantic conventions this
+// module vendors predate the VCS and CI resource conventions.
+var (
+	vcsRepositoryURLKey = attribute.Key("vcs.repository_url")
+	vcsRevisionKey      = attribute.Key("vcs.revision")
+	vcsBranchKey        = attribute.Key("vcs.branch")
+	ciProviderKey       = attribute.Key("ci.provider")
+	ciJobIDKey          = attribute.Key("ci.job.id")
+	ciBuildURLKey       = attribute.Key("ci.build.url")
+)
+
+// runResourceAttributes returns the Go runtime, host and process resource
+// attributes describing this invocation of go-test-trace.
+func runResourceAttributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.ProcessRuntimeNameKey.String("go"),
+		semconv.ProcessRuntimeVersionKey.String(runtime.Version()),
+		semconv.ProcessCommandArgsKey.StringSlice(os.Args),
+		osTypeAttribute(),
+	}
+	if host, err := os.Hostname(); err == nil {
+		attrs = append(attrs, semconv.HostNameKey.String(host))
+	}
+	attrs = append(attrs, vcsAttributes()...)
+	attrs = append(attrs, ciAttributes()...)
+	return attrs
+}
+
+func osTypeAttribute() attribute.KeyValue {
+	switch runtime.GOOS {
+	case "linux":
+		return semconv.OSTypeLinux
+	case "darwin":
+		return semconv.OSTypeDarwin
+	case "windows":
+		return semconv.OSTypeWindows
+	case "freebsd":
+		return semconv.OSTypeFreeBSD
+	case "netbsd":
+		return semconv.OSTypeNetBSD
+	case "openbsd":
+		return semconv.OSTypeOpenBSD
+	case "solaris":
+		return semconv.OSTypeSolaris
+	case "aix":
+		return semconv.OSTypeAIX
+	default:
+		return semconv.OSTypeKey.String(runtime.GOOS)
+	}
+}
+
+// vcsAttributes reports a VCS revision and branch. It prefers the VCS info
+// Go embeds in the go-test-trace binary (debug.ReadBuildInfo, available
+// since Go 1.18), which describes the checkout the binary was built from,
+// and otherwise asks git about the checkout in the current working
+// directory. The repository URL always comes from the working directory's
+// git remote.
+func vcsAttributes() []attribute.KeyValue {
+	if rev, branch, ok := vcsFromBuildInfo(); ok {
+		return vcsKeyValues(gitRemoteURL(), rev, branch)
+	}
+	rev, _ := runGit("rev-parse", "HEAD")
+	branch, _ := runGit("rev-parse", "--abbrev-ref", "HEAD")
+	if rev == "" && branch == "" {
+		return nil
+	}
+	return vcsKeyValues(gitRemoteURL(), rev, branch)
+}
+
+func vcsKeyValues(repoURL, rev, branch string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if repoURL != "" {
+		attrs = append(attrs, vcsRepositoryURLKey.String(repoURL))
+	}
+	if rev != "" {
+		attrs = append(attrs, vcsRevisionKey.String(rev))
+	}
+	if branch != "" {
+		attrs = append(attrs, vcsBranchKey.String(branch))
+	}
+	return attrs
+}
+
+func vcsFromBuildInfo() (rev, branch string, ok bool) {
+	info, available := debug.ReadBuildInfo()
+	if !available {
+		return "", "", false
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.branch":
+			branch = s.Value
+		}
+	}
+	return rev, branch, rev != "" || branch != ""
+}
+
+func gitRemoteURL() string {
+	url, _ := runGit("config", "--get", "remote.origin.url")
+	return url
+}
+
+func runGit(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ciAttributes auto-detects well-known CI providers from their environment
+// variables.
+func ciAttributes() []attribute.KeyValue {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return []attribute.KeyValue{
+			ciProviderKey.String("github-actions"),
+			ciJobIDKey.String(os.Getenv("GITHUB_RUN_ID")),
+			ciBuildURLKey.String(strings.TrimSuffix(os.Getenv("GITHUB_SERVER_URL"), "/") + "/" +
+				os.Getenv("GITHUB_REPOSITORY") + "/actions/runs/" + os.Getenv("GITHUB_RUN_ID")),
+		}
+	case os.Getenv("GITLAB_CI") == "true":
+		return []attribute.KeyValue{
+			ciProviderKey.String("gitlab-ci"),
+			ciJobIDKey.String(os.Getenv("CI_JOB_ID")),
+			ciBuildURLKey.String(os.Getenv("CI_JOB_URL")),
+		}
+	case os.Getenv("CIRCLECI") == "true":
+		return []attribute.KeyValue{
+			ciProviderKey.String("circleci"),
+			ciJobIDKey.String(os.Getenv("CIRCLE_BUILD_NUM")),
+			ciBuildURLKey.String(os.Getenv("CIRCLE_BUILD_URL")),
+		}
+	default:
+		return nil
+	}
+}
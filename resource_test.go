@@ -0,0 +1,152 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// ciEnvVars lists every environment variable ciAttributes reads, so tests
+// can clear them all before setting the ones a case cares about.
+var ciEnvVars = []string{
+	"GITHUB_ACTIONS", "GITHUB_RUN_ID", "GITHUB_SERVER_URL", "GITHUB_REPOSITORY",
+	"GITLAB_CI", "CI_JOB_ID", "CI_JOB_URL",
+	"CIRCLECI", "CIRCLE_BUILD_NUM", "CIRCLE_BUILD_URL",
+}
+
+func TestCIAttributes(t *testing.T) {
+	tests := []struct {
+		description string
+		env         map[string]string
+		want        []attribute.KeyValue
+	}{
+		{
+			description: "no known CI environment detected",
+			want:        nil,
+		},
+		{
+			description: "github actions",
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_RUN_ID":     "123",
+				"GITHUB_SERVER_URL": "https://github.com",
+				"GITHUB_REPOSITORY": "rakyll/go-test-xray",
+			},
+			want: []attribute.KeyValue{
+				ciProviderKey.String("github-actions"),
+				ciJobIDKey.String("123"),
+				ciBuildURLKey.String("https://github.com/rakyll/go-test-xray/actions/runs/123"),
+			},
+		},
+		{
+			description: "gitlab ci",
+			env: map[string]string{
+				"GITLAB_CI":  "true",
+				"CI_JOB_ID":  "456",
+				"CI_JOB_URL": "https://gitlab.com/rakyll/go-test-xray/-/jobs/456",
+			},
+			want: []attribute.KeyValue{
+				ciProviderKey.String("gitlab-ci"),
+				ciJobIDKey.String("456"),
+				ciBuildURLKey.String("https://gitlab.com/rakyll/go-test-xray/-/jobs/456"),
+			},
+		},
+		{
+			description: "circleci",
+			env: map[string]string{
+				"CIRCLECI":         "true",
+				"CIRCLE_BUILD_NUM": "789",
+				"CIRCLE_BUILD_URL": "https://circleci.com/gh/rakyll/go-test-xray/789",
+			},
+			want: []attribute.KeyValue{
+				ciProviderKey.String("circleci"),
+				ciJobIDKey.String("789"),
+				ciBuildURLKey.String("https://circleci.com/gh/rakyll/go-test-xray/789"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			for _, k := range ciEnvVars {
+				saved, had := os.LookupEnv(k)
+				os.Unsetenv(k)
+				if had {
+					defer os.Setenv(k, saved)
+				} else {
+					defer os.Unsetenv(k)
+				}
+			}
+			for k, v := range tt.env {
+				os.Setenv(k, v)
+			}
+
+			got := ciAttributes()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ciAttributes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVcsKeyValues(t *testing.T) {
+	tests := []struct {
+		description string
+		repoURL     string
+		rev         string
+		branch      string
+		want        []attribute.KeyValue
+	}{
+		{
+			description: "all fields present",
+			repoURL:     "https://github.com/rakyll/go-test-xray",
+			rev:         "abc123",
+			branch:      "main",
+			want: []attribute.KeyValue{
+				vcsRepositoryURLKey.String("https://github.com/rakyll/go-test-xray"),
+				vcsRevisionKey.String("abc123"),
+				vcsBranchKey.String("main"),
+			},
+		},
+		{
+			description: "no repo URL available",
+			rev:         "abc123",
+			branch:      "main",
+			want: []attribute.KeyValue{
+				vcsRevisionKey.String("abc123"),
+				vcsBranchKey.String("main"),
+			},
+		},
+		{
+			description: "nothing available",
+			want:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := vcsKeyValues(tt.repoURL, tt.rev, tt.branch)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("vcsKeyValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOSTypeAttribute(t *testing.T) {
+	// osTypeAttribute switches on runtime.GOOS, which is fixed for this test
+	// binary; just assert it returns the semconv key and a non-empty value
+	// rather than hardcoding the host OS.
+	got := osTypeAttribute()
+	if got.Key != semconv.OSTypeKey {
+		t.Errorf("osTypeAttribute() key = %v, want %v", got.Key, semconv.OSTypeKey)
+	}
+	if got.Value.AsString() == "" {
+		t.Errorf("osTypeAttribute() value is empty")
+	}
+}
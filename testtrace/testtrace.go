@@ -0,0 +1,98 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package testtrace lets code under test join the trace go-test-trace
+// started for it.
+package testtrace
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// StartSpan starts a span for t, parented to the TRACEPARENT go-test-trace
+// sets on the `go test` process, so it joins the same trace as the overall
+// run. TRACEPARENT is one fixed value for the whole process, not a distinct
+// one per test, so the returned span is a child of go-test-trace's root
+// span rather than of t's own span — it will show up as a sibling of t,
+// not nested under it. If TRACEPARENT isn't set, because the test wasn't
+// run under go-test-trace, StartSpan starts an ordinary root span instead.
+//
+// go-test-trace itself only runs a TracerProvider in its own parent
+// process, not in the `go test` child this code executes in, so StartSpan
+// uses whatever TracerProvider the test binary already registered globally
+// if there is one, or otherwise lazily builds its own against
+// OTEL_EXPORTER_OTLP_ENDPOINT so the span actually reaches a collector.
+//
+// The span is ended automatically via t.Cleanup.
+func StartSpan(t *testing.T) (context.Context, oteltrace.Span) {
+	t.Helper()
+	ctx := propagation.TraceContext{}.Extract(context.Background(), envCarrier{})
+	ctx, span := tracer().Start(ctx, t.Name())
+	t.Cleanup(func() { span.End() })
+	return ctx, span
+}
+
+var (
+	tracerOnce sync.Once
+	fallback   oteltrace.Tracer
+)
+
+// tracer returns the Tracer StartSpan should use: the global TracerProvider
+// if the test binary already registered a real one, or else a minimal one
+// this package builds and caches for the life of the process.
+func tracer() oteltrace.Tracer {
+	// A registered SDK TracerProvider exports spans (among other things,
+	// via ForceFlush); the default, unconfigured global provider doesn't
+	// implement that, so this distinguishes "already set up" from "not".
+	if _, ok := otel.GetTracerProvider().(interface {
+		ForceFlush(context.Context) error
+	}); ok {
+		return otel.Tracer("testtrace")
+	}
+	tracerOnce.Do(func() { fallback = newFallbackTracer() })
+	return fallback
+}
+
+// newFallbackTracer builds a TracerProvider exporting over otlp-grpc,
+// honoring OTEL_EXPORTER_OTLP_ENDPOINT the same way the exporters in the
+// parent go-test-trace process do. Spans are flushed synchronously as they
+// end, since this package has no hook to flush a batch on process exit.
+func newFallbackTracer() oteltrace.Tracer {
+	exp, err := otlptracegrpc.New(context.Background(),
+		otlptracegrpc.WithInsecure(),
+		otlptracegrpc.WithTimeout(100*time.Millisecond),
+	)
+	if err != nil {
+		return oteltrace.NewNoopTracerProvider().Tracer("testtrace")
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sdktrace.NewSimpleSpanProcessor(exp)))
+	return tp.Tracer("testtrace")
+}
+
+// envCarrier reads the W3C traceparent go-test-trace sets on the
+// environment of the `go test` process it spawns.
+type envCarrier struct{}
+
+func (envCarrier) Get(key string) string {
+	if key == "traceparent" {
+		return os.Getenv("TRACEPARENT")
+	}
+	return ""
+}
+
+func (envCarrier) Set(key, value string) {}
+
+func (envCarrier) Keys() []string {
+	return []string{"traceparent"}
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testtrace
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestEnvCarrierGet(t *testing.T) {
+	tests := []struct {
+		description string
+		traceparent string
+		key         string
+		want        string
+	}{
+		{
+			description: "traceparent is set",
+			traceparent: "00-b75e0c55a4c873f439336e69d0e202c3-28f5baa7fc8edd32-01",
+			key:         "traceparent",
+			want:        "00-b75e0c55a4c873f439336e69d0e202c3-28f5baa7fc8edd32-01",
+		},
+		{
+			description: "traceparent is unset",
+			traceparent: "",
+			key:         "traceparent",
+			want:        "",
+		},
+		{
+			description: "unrelated key is ignored",
+			traceparent: "00-b75e0c55a4c873f439336e69d0e202c3-28f5baa7fc8edd32-01",
+			key:         "tracestate",
+			want:        "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			saved, had := os.LookupEnv("TRACEPARENT")
+			defer func() {
+				if had {
+					os.Setenv("TRACEPARENT", saved)
+				} else {
+					os.Unsetenv("TRACEPARENT")
+				}
+			}()
+
+			if tt.traceparent == "" {
+				os.Unsetenv("TRACEPARENT")
+			} else {
+				os.Setenv("TRACEPARENT", tt.traceparent)
+			}
+
+			if got := (envCarrier{}).Get(tt.key); got != tt.want {
+				t.Errorf("envCarrier.Get(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvCarrierKeys(t *testing.T) {
+	want := []string{"traceparent"}
+	if got := (envCarrier{}).Keys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("envCarrier.Keys() = %v, want %v", got, want)
+	}
+}